@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/cactus/go-statsd-client/v5/statsd"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/vposhamod/watermill-benchmark/pkg"
+)
+
+func main() {
+	pubSubName := flag.String("pubsub", "gochannel", "PubSub to benchmark, see pkg.pubSubDefinitions for the list of supported names")
+	topic := flag.String("topic", "benchmark", "topic to publish/consume messages on")
+	messagesCount := flag.Int("messages-count", 0, "number of messages to publish/consume, 0 uses the PubSub's default")
+	messageSize := flag.Int("message-size", 16, "size in bytes of each message payload; exact for random-bytes, a minimum for the structured codecs")
+	mode := flag.String("mode", "publish", "benchmark mode: publish, consume, roundtrip or dlq")
+	codec := flag.String("codec", "random-bytes", "payload codec: random-bytes, json, protobuf, senml or senml-cbor")
+	failureRate := flag.Float64("failure-rate", 0.1, "fraction of messages (0-1) nacked to the DLQ, used by the dlq mode")
+	dlqTopic := flag.String("dlq-topic", "benchmark-dlq", "topic poisoned messages are routed to, used by the dlq mode")
+	metricsAddr := flag.String("metrics-addr", "", "if set, serve Prometheus metrics on this address (e.g. :8081) for the duration of the run")
+	statsdAddr := flag.String("statsd-addr", "", "if set, additionally mirror metrics to this StatsD address")
+
+	flag.Parse()
+
+	ps, err := pkg.NewPubSub(*pubSubName, *topic, *messagesCount, *messageSize, *codec)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer ps.Close()
+
+	if *metricsAddr != "" {
+		var statsdClient statsd.Statter
+		if *statsdAddr != "" {
+			statsdClient, err = statsd.NewClientWithConfig(&statsd.ClientConfig{Address: *statsdAddr})
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer statsdClient.Close()
+		}
+
+		registry := prometheus.NewRegistry()
+		ps = ps.WithMetrics(*pubSubName, registry, statsdClient)
+
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+		go func() {
+			if err := http.ListenAndServe(*metricsAddr, mux); err != nil {
+				log.Println("metrics server:", err)
+			}
+		}()
+	}
+
+	if *mode == "dlq" {
+		if err := ps.RunWithDLQ(*failureRate, *dlqTopic); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if err := ps.RunBenchmark(*mode); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,116 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+)
+
+const (
+	// latency is recorded in microseconds, up to an hour, with 3 significant figures
+	latencyHistogramMin     = 1
+	latencyHistogramMax     = int64(time.Hour / time.Microsecond)
+	latencyHistogramSigFigs = 3
+	statsTickInterval       = 5 * time.Second
+)
+
+// ConsumeMessages subscribes to ps.Topic, acks every message and records
+// end-to-end latency from the publish timestamp set by PublishMessages.
+func (ps PubSub) ConsumeMessages() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	messages, err := ps.Subscriber.Subscribe(ctx, ps.Topic)
+	if err != nil {
+		return err
+	}
+
+	histogram := hdrhistogram.New(latencyHistogramMin, latencyHistogramMax, latencyHistogramSigFigs)
+
+	var consumed int64
+	start := time.Now()
+
+	ticker := time.NewTicker(statsTickInterval)
+	defer ticker.Stop()
+
+	for consumed < int64(ps.MessagesCount) {
+		select {
+		case msg, ok := <-messages:
+			if !ok {
+				return fmt.Errorf("subscriber closed after consuming %d/%d messages", consumed, ps.MessagesCount)
+			}
+
+			if publishedAt, ok := msg.Metadata[publishedAtMetadataKey]; ok {
+				if err := recordLatency(histogram, publishedAt); err != nil {
+					return err
+				}
+			}
+
+			msg.Ack()
+			atomic.AddInt64(&consumed, 1)
+		case now := <-ticker.C:
+			fmt.Printf(
+				"consumed %d/%d messages, %f msg/s, p50=%s p95=%s p99=%s p999=%s\n",
+				consumed, ps.MessagesCount, float64(consumed)/now.Sub(start).Seconds(),
+				latencyAtPercentile(histogram, 50), latencyAtPercentile(histogram, 95),
+				latencyAtPercentile(histogram, 99), latencyAtPercentile(histogram, 99.9),
+			)
+		}
+	}
+
+	elapsed := time.Now().Sub(start)
+	fmt.Printf(
+		"consumed %d messages in %s, %f msg/s, p50=%s p95=%s p99=%s p999=%s\n",
+		ps.MessagesCount, elapsed, float64(ps.MessagesCount)/elapsed.Seconds(),
+		latencyAtPercentile(histogram, 50), latencyAtPercentile(histogram, 95),
+		latencyAtPercentile(histogram, 99), latencyAtPercentile(histogram, 99.9),
+	)
+
+	return nil
+}
+
+// RunBenchmark runs mode "publish", "consume" or "roundtrip" (both at once).
+func (ps PubSub) RunBenchmark(mode string) error {
+	switch mode {
+	case "publish":
+		return ps.PublishMessages()
+	case "consume":
+		return ps.ConsumeMessages()
+	case "roundtrip":
+		errs := make(chan error, 2)
+
+		go func() {
+			errs <- ps.ConsumeMessages()
+		}()
+		go func() {
+			errs <- ps.PublishMessages()
+		}()
+
+		for i := 0; i < 2; i++ {
+			if err := <-errs; err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown benchmark mode: %s", mode)
+	}
+}
+
+func recordLatency(histogram *hdrhistogram.Histogram, publishedAt string) error {
+	publishedAtNano, err := strconv.ParseInt(publishedAt, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid %s metadata value %q: %w", publishedAtMetadataKey, publishedAt, err)
+	}
+
+	latency := time.Since(time.Unix(0, publishedAtNano))
+	return histogram.RecordValue(latency.Microseconds())
+}
+
+func latencyAtPercentile(histogram *hdrhistogram.Histogram, percentile float64) time.Duration {
+	return time.Duration(histogram.ValueAtPercentile(percentile)) * time.Microsecond
+}
@@ -0,0 +1,95 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/ThreeDotsLabs/watermill/message/router/middleware"
+)
+
+// RunWithDLQ nacks a failureRate (0-1) fraction of messages to dlqTopic via
+// a Poison middleware, and reports delivered vs. DLQ throughput/latency separately.
+func (ps PubSub) RunWithDLQ(failureRate float64, dlqTopic string) error {
+	router, err := message.NewRouter(message.RouterConfig{}, logger)
+	if err != nil {
+		return err
+	}
+
+	poisonMiddleware, err := middleware.PoisonQueue(ps.Publisher, dlqTopic)
+	if err != nil {
+		return err
+	}
+	router.AddMiddleware(poisonMiddleware)
+
+	deliveredHistogram := hdrhistogram.New(latencyHistogramMin, latencyHistogramMax, latencyHistogramSigFigs)
+	dlqHistogram := hdrhistogram.New(latencyHistogramMin, latencyHistogramMax, latencyHistogramSigFigs)
+
+	var delivered, dlqd int64
+
+	router.AddNoPublisherHandler("dlq-benchmark", ps.Topic, ps.Subscriber, func(msg *message.Message) error {
+		if rand.Float64() < failureRate {
+			atomic.AddInt64(&dlqd, 1)
+			return fmt.Errorf("simulated failure for message %s", msg.UUID)
+		}
+
+		if publishedAt, ok := msg.Metadata[publishedAtMetadataKey]; ok {
+			if err := recordLatency(deliveredHistogram, publishedAt); err != nil {
+				return err
+			}
+		}
+		atomic.AddInt64(&delivered, 1)
+		return nil
+	})
+
+	var dlqRedelivered int64
+
+	router.AddNoPublisherHandler("dlq-redelivery", dlqTopic, ps.Subscriber, func(msg *message.Message) error {
+		if publishedAt, ok := msg.Metadata[publishedAtMetadataKey]; ok {
+			if err := recordLatency(dlqHistogram, publishedAt); err != nil {
+				return err
+			}
+		}
+		atomic.AddInt64(&dlqRedelivered, 1)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	start := time.Now()
+
+	go func() {
+		if err := ps.PublishMessages(); err != nil {
+			panic(err)
+		}
+	}()
+
+	go func() {
+		for atomic.LoadInt64(&delivered)+atomic.LoadInt64(&dlqRedelivered) < int64(ps.MessagesCount) {
+			time.Sleep(100 * time.Millisecond)
+		}
+		cancel()
+	}()
+
+	if err := router.Run(ctx); err != nil && err != context.Canceled {
+		return err
+	}
+
+	elapsed := time.Now().Sub(start)
+	deliveredCount := atomic.LoadInt64(&delivered)
+	dlqRedeliveredCount := atomic.LoadInt64(&dlqRedelivered)
+	fmt.Printf(
+		"delivered %d (%f msg/s), dlq %d/%d redelivered (%f msg/s), in %s, delivered p99=%s dlq p99=%s\n",
+		deliveredCount, float64(deliveredCount)/elapsed.Seconds(),
+		dlqRedeliveredCount, atomic.LoadInt64(&dlqd), float64(dlqRedeliveredCount)/elapsed.Seconds(), elapsed,
+		latencyAtPercentile(deliveredHistogram, 99), latencyAtPercentile(dlqHistogram, 99),
+	)
+
+	return nil
+}
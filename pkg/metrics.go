@@ -0,0 +1,166 @@
+package pkg
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/cactus/go-statsd-client/v5/statsd"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+)
+
+const workerIDMetadataKey = "worker_id"
+
+var metricsLabelNames = []string{"pubsub", "topic", "worker_id"}
+
+// metrics holds the counters/histograms exported for a benchmark run.
+type metrics struct {
+	pubsubName string
+
+	published *prometheus.CounterVec
+	consumed  *prometheus.CounterVec
+	errors    *prometheus.CounterVec
+	sizeBytes *prometheus.HistogramVec
+	latency   *prometheus.HistogramVec
+
+	statsdClient statsd.Statter
+}
+
+func newMetrics(registry prometheus.Registerer, pubsubName string, statsdClient statsd.Statter) *metrics {
+	m := &metrics{
+		pubsubName: pubsubName,
+		published: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "watermill_benchmark",
+			Name:      "messages_published_total",
+			Help:      "Number of messages successfully published.",
+		}, metricsLabelNames),
+		consumed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "watermill_benchmark",
+			Name:      "messages_consumed_total",
+			Help:      "Number of messages successfully consumed.",
+		}, metricsLabelNames),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "watermill_benchmark",
+			Name:      "errors_total",
+			Help:      "Number of publish errors.",
+		}, metricsLabelNames),
+		sizeBytes: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "watermill_benchmark",
+			Name:      "message_size_bytes",
+			Help:      "Encoded message payload size.",
+			Buckets:   prometheus.ExponentialBuckets(64, 4, 8),
+		}, metricsLabelNames),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "watermill_benchmark",
+			Name:      "consume_latency_seconds",
+			Help:      "End-to-end publish-to-consume latency.",
+			Buckets:   prometheus.DefBuckets,
+		}, metricsLabelNames),
+		statsdClient: statsdClient,
+	}
+
+	registry.MustRegister(m.published, m.consumed, m.errors, m.sizeBytes, m.latency)
+
+	return m
+}
+
+func (m *metrics) labels(topic string, msg *message.Message) prometheus.Labels {
+	workerID := msg.Metadata.Get(workerIDMetadataKey)
+	if workerID == "" {
+		workerID = "unknown"
+	}
+
+	return prometheus.Labels{"pubsub": m.pubsubName, "topic": topic, "worker_id": workerID}
+}
+
+func (m *metrics) statsdStat(name string, labels prometheus.Labels) string {
+	return "watermill_benchmark." + name + "." + m.pubsubName + "." + labels["topic"] + "." + labels["worker_id"]
+}
+
+// WithMetrics wraps ps.Publisher and ps.Subscriber to export counters and
+// size/latency histograms to registry, optionally mirroring to statsdClient.
+func (ps PubSub) WithMetrics(name string, registry prometheus.Registerer, statsdClient statsd.Statter) PubSub {
+	m := newMetrics(registry, name, statsdClient)
+
+	ps.Publisher = &metricsPublisher{Publisher: ps.Publisher, metrics: m}
+	ps.Subscriber = &metricsSubscriber{Subscriber: ps.Subscriber, metrics: m}
+
+	return ps
+}
+
+type metricsPublisher struct {
+	message.Publisher
+	metrics *metrics
+}
+
+func (p *metricsPublisher) Publish(topic string, messages ...*message.Message) error {
+	err := p.Publisher.Publish(topic, messages...)
+
+	for _, msg := range messages {
+		labels := p.metrics.labels(topic, msg)
+
+		p.metrics.sizeBytes.With(labels).Observe(float64(len(msg.Payload)))
+		if err != nil {
+			p.metrics.errors.With(labels).Inc()
+		} else {
+			p.metrics.published.With(labels).Inc()
+		}
+
+		if p.metrics.statsdClient != nil {
+			_ = p.metrics.statsdClient.Inc(p.metrics.statsdStat("messages_published", labels), 1, 1)
+			_ = p.metrics.statsdClient.Gauge(p.metrics.statsdStat("message_size_bytes", labels), int64(len(msg.Payload)), 1)
+		}
+	}
+
+	return err
+}
+
+type metricsSubscriber struct {
+	message.Subscriber
+	metrics *metrics
+}
+
+func (s *metricsSubscriber) Subscribe(ctx context.Context, topic string) (<-chan *message.Message, error) {
+	in, err := s.Subscriber.Subscribe(ctx, topic)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan *message.Message)
+
+	go func() {
+		defer close(out)
+
+		for msg := range in {
+			labels := s.metrics.labels(topic, msg)
+			s.metrics.consumed.With(labels).Inc()
+
+			if publishedAt, ok := msg.Metadata[publishedAtMetadataKey]; ok {
+				if latency, err := latencySince(publishedAt); err == nil {
+					s.metrics.latency.With(labels).Observe(latency.Seconds())
+					if s.metrics.statsdClient != nil {
+						_ = s.metrics.statsdClient.Timing(s.metrics.statsdStat("consume_latency_ms", labels), latency.Milliseconds(), 1)
+					}
+				}
+			}
+
+			if s.metrics.statsdClient != nil {
+				_ = s.metrics.statsdClient.Inc(s.metrics.statsdStat("messages_consumed", labels), 1, 1)
+			}
+
+			out <- msg
+		}
+	}()
+
+	return out, nil
+}
+
+func latencySince(publishedAt string) (time.Duration, error) {
+	publishedAtNano, err := strconv.ParseInt(publishedAt, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return time.Since(time.Unix(0, publishedAtNano)), nil
+}
@@ -0,0 +1,120 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+)
+
+// mqttEnvelope is the wire format for messages sent over MQTT: paho has no
+// notion of message metadata, so UUID and Metadata are gob-encoded alongside
+// the payload.
+type mqttEnvelope struct {
+	UUID     string
+	Metadata message.Metadata
+	Payload  []byte
+}
+
+// newMQTTClient connects a paho client to brokerURL under clientID.
+func newMQTTClient(brokerURL, clientID string, cleanSession bool) (paho.Client, error) {
+	opts := paho.NewClientOptions().
+		AddBroker(brokerURL).
+		SetClientID(clientID).
+		SetCleanSession(cleanSession).
+		SetAutoReconnect(true)
+
+	client := paho.NewClient(opts)
+	if token := client.Connect(); token.Wait(); token.Error() != nil {
+		return nil, token.Error()
+	}
+	return client, nil
+}
+
+// mqttPublisher publishes watermill messages as gob-encoded MQTT PUBLISH packets.
+type mqttPublisher struct {
+	client paho.Client
+	qos    byte
+}
+
+func newMQTTPublisher(client paho.Client, qos byte) *mqttPublisher {
+	return &mqttPublisher{client: client, qos: qos}
+}
+
+func (p *mqttPublisher) Publish(topic string, messages ...*message.Message) error {
+	for _, msg := range messages {
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(mqttEnvelope{UUID: msg.UUID, Metadata: msg.Metadata, Payload: msg.Payload}); err != nil {
+			return err
+		}
+
+		token := p.client.Publish(topic, p.qos, false, buf.Bytes())
+		if token.Wait(); token.Error() != nil {
+			return token.Error()
+		}
+	}
+	return nil
+}
+
+func (p *mqttPublisher) Close() error {
+	p.client.Disconnect(250)
+	return nil
+}
+
+// mqttSubscriber subscribes to an MQTT topic and decodes mqttEnvelope payloads.
+type mqttSubscriber struct {
+	client paho.Client
+	qos    byte
+}
+
+func newMQTTSubscriber(client paho.Client, qos byte) *mqttSubscriber {
+	return &mqttSubscriber{client: client, qos: qos}
+}
+
+func (s *mqttSubscriber) Subscribe(ctx context.Context, topic string) (<-chan *message.Message, error) {
+	out := make(chan *message.Message)
+
+	handler := func(_ paho.Client, pahoMsg paho.Message) {
+		var envelope mqttEnvelope
+		if err := gob.NewDecoder(bytes.NewReader(pahoMsg.Payload())).Decode(&envelope); err != nil {
+			return
+		}
+
+		msg := message.NewMessage(envelope.UUID, envelope.Payload)
+		msg.Metadata = envelope.Metadata
+
+		select {
+		case out <- msg:
+		case <-ctx.Done():
+			return
+		}
+
+		select {
+		case <-msg.Acked():
+			pahoMsg.Ack()
+		case <-msg.Nacked():
+		case <-ctx.Done():
+		}
+	}
+
+	if token := s.client.Subscribe(topic, s.qos, handler); token.Wait(); token.Error() != nil {
+		close(out)
+		return nil, token.Error()
+	}
+
+	go func() {
+		<-ctx.Done()
+		s.client.Unsubscribe(topic)
+		close(out)
+	}()
+
+	return out, nil
+}
+
+func (s *mqttSubscriber) Close() error {
+	s.client.Disconnect(250)
+	return nil
+}
@@ -0,0 +1,159 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/ThreeDotsLabs/watermill"
+)
+
+// PayloadGenerator produces message payloads for the benchmark.
+type PayloadGenerator interface {
+	Generate() ([]byte, error)
+}
+
+// NewPayloadGenerator builds the PayloadGenerator for the given codec name.
+func NewPayloadGenerator(codec string, size int) (PayloadGenerator, error) {
+	switch codec {
+	case "", "random-bytes":
+		return randomBytesPayloadGenerator{size: size}, nil
+	case "json":
+		return jsonPayloadGenerator{size: size}, nil
+	case "protobuf":
+		return protobufPayloadGenerator{size: size}, nil
+	case "senml":
+		return senmlPayloadGenerator{size: size, format: "json"}, nil
+	case "senml-cbor":
+		return senmlPayloadGenerator{size: size, format: "cbor"}, nil
+	default:
+		return nil, fmt.Errorf("unknown payload codec: %s", codec)
+	}
+}
+
+type randomBytesPayloadGenerator struct {
+	size int
+}
+
+func (g randomBytesPayloadGenerator) Generate() ([]byte, error) {
+	payload := make([]byte, g.size)
+	if _, err := rand.Read(payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// telemetryReading is shared by the json and protobuf generators.
+type telemetryReading struct {
+	DeviceID  string  `json:"device_id"`
+	Timestamp int64   `json:"timestamp"`
+	Value     float64 `json:"value"`
+	Unit      string  `json:"unit"`
+	Padding   string  `json:"padding,omitempty"`
+}
+
+func newTelemetryReading() telemetryReading {
+	return telemetryReading{
+		DeviceID:  watermill.NewShortUUID(),
+		Timestamp: time.Now().UnixNano(),
+		Value:     rand.Float64() * 100,
+		Unit:      "celsius",
+	}
+}
+
+type jsonPayloadGenerator struct {
+	size int
+}
+
+func (g jsonPayloadGenerator) Generate() ([]byte, error) {
+	reading := newTelemetryReading()
+	return sizeAdjustedPayload(g.size, func(padding string) ([]byte, error) {
+		reading.Padding = padding
+		return json.Marshal(reading)
+	})
+}
+
+type protobufPayloadGenerator struct {
+	size int
+}
+
+func (g protobufPayloadGenerator) Generate() ([]byte, error) {
+	reading := newTelemetryReading()
+
+	return sizeAdjustedPayload(g.size, func(padding string) ([]byte, error) {
+		msg, err := structpb.NewStruct(map[string]interface{}{
+			"device_id": reading.DeviceID,
+			"timestamp": reading.Timestamp,
+			"value":     reading.Value,
+			"unit":      reading.Unit,
+			"padding":   padding,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return proto.Marshal(msg)
+	})
+}
+
+// senmlRecord is a single SenML (RFC 8428) measurement.
+type senmlRecord struct {
+	Name    string  `json:"n" cbor:"n"`
+	Unit    string  `json:"u" cbor:"u"`
+	Value   float64 `json:"v" cbor:"v"`
+	Time    float64 `json:"t" cbor:"t"`
+	Padding string  `json:"p,omitempty" cbor:"p,omitempty"`
+}
+
+type senmlPayloadGenerator struct {
+	size   int
+	format string // "json" or "cbor"
+}
+
+func (g senmlPayloadGenerator) Generate() ([]byte, error) {
+	record := senmlRecord{
+		Name:  "urn:dev:" + watermill.NewShortUUID(),
+		Unit:  "Cel",
+		Value: rand.Float64() * 100,
+		Time:  float64(time.Now().UnixNano()) / float64(time.Second),
+	}
+
+	marshal := json.Marshal
+	if g.format == "cbor" {
+		marshal = cbor.Marshal
+	}
+
+	return sizeAdjustedPayload(g.size, func(padding string) ([]byte, error) {
+		record.Padding = padding
+		return marshal([]senmlRecord{record})
+	})
+}
+
+// sizeAdjustedPayload measures build's unpadded output, then re-encodes once
+// with enough padding appended to reach target.
+func sizeAdjustedPayload(target int, build func(padding string) ([]byte, error)) ([]byte, error) {
+	unpadded, err := build("")
+	if err != nil {
+		return nil, err
+	}
+
+	if deficit := target - len(unpadded); deficit > 0 {
+		return build(randomString(deficit))
+	}
+	return unpadded, nil
+}
+
+func randomString(length int) string {
+	const alphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+	out := make([]byte, length)
+	for i := range out {
+		out[i] = alphabet[rand.Intn(len(alphabet))]
+	}
+	return string(out)
+}
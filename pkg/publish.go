@@ -3,14 +3,21 @@ package pkg
 import (
 	"fmt"
 	"math/rand"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/HdrHistogram/hdrhistogram-go"
+
 	"github.com/ThreeDotsLabs/watermill"
 	"github.com/ThreeDotsLabs/watermill/message"
 	"github.com/ThreeDotsLabs/watermill/message/router/middleware"
 )
 
+// publishedAtMetadataKey carries the publish-side timestamp (UnixNano, as a
+// string) so consumers can compute end-to-end latency.
+const publishedAtMetadataKey = "published_at"
+
 func (ps PubSub) PublishMessages() error {
 	rand.Seed(time.Now().UnixNano())
 
@@ -22,28 +29,42 @@ func (ps PubSub) PublishMessages() error {
 
 	addMsg := make(chan *message.Message)
 
+	// encoded size distribution, so results are comparable across codecs
+	sizeHistogram := hdrhistogram.New(1, 1024*1024, 3)
+	var sizeHistogramMu sync.Mutex
+
 	start := time.Now()
 
 	for num := 0; num < workers; num++ {
-		go func() {
+		go func(workerID int) {
 			defer wg.Done()
 
 			for msg := range addMsg {
 				// using function from middleware to set correlation id, useful for debugging
 				middleware.SetCorrelationID(watermill.NewShortUUID(), msg)
+				msg.Metadata.Set(publishedAtMetadataKey, strconv.FormatInt(time.Now().UnixNano(), 10))
+				msg.Metadata.Set(workerIDMetadataKey, strconv.Itoa(workerID))
 
 				if err := ps.Publisher.Publish(ps.Topic, msg); err != nil {
 					panic(err)
 				}
 			}
-		}()
+		}(num)
 	}
 
-	msgPayload, err := ps.payload()
-	if err != nil {
-		return err
-	}
 	for ; messagesLeft > 0; messagesLeft-- {
+		msgPayload, err := ps.PayloadGenerator.Generate()
+		if err != nil {
+			return err
+		}
+
+		sizeHistogramMu.Lock()
+		if err := sizeHistogram.RecordValue(int64(len(msgPayload))); err != nil {
+			sizeHistogramMu.Unlock()
+			return err
+		}
+		sizeHistogramMu.Unlock()
+
 		msg := message.NewMessage(watermill.NewULID(), msgPayload)
 		addMsg <- msg
 	}
@@ -52,17 +73,11 @@ func (ps PubSub) PublishMessages() error {
 	wg.Wait()
 
 	elapsed := time.Now().Sub(start)
-	fmt.Printf("added %d messages in %s, %f msg/s\n", ps.MessagesCount, elapsed, float64(ps.MessagesCount)/elapsed.Seconds())
+	fmt.Printf(
+		"added %d messages in %s, %f msg/s, encoded size p50=%d p95=%d p99=%d bytes\n",
+		ps.MessagesCount, elapsed, float64(ps.MessagesCount)/elapsed.Seconds(),
+		sizeHistogram.ValueAtPercentile(50), sizeHistogram.ValueAtPercentile(95), sizeHistogram.ValueAtPercentile(99),
+	)
 
 	return nil
 }
-
-func (ps PubSub) payload() ([]byte, error) {
-	msgPayload := make([]byte, ps.MessageSize)
-	_, err := rand.Read(msgPayload)
-	if err != nil {
-		return nil, err
-	}
-
-	return msgPayload, nil
-}
@@ -0,0 +1,238 @@
+package pkg
+
+import (
+	stdSQL "database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill-sql/pkg/sql"
+	"github.com/ThreeDotsLabs/watermill/message"
+)
+
+// partitionWindow is how many range partitions, starting with the current
+// one, are pre-created at startup.
+const partitionWindow = 3
+
+// PostgreSQLPartitionedSchema range-partitions the messages table by month.
+type PostgreSQLPartitionedSchema struct {
+	PostgreSQLSchema
+}
+
+func (p PostgreSQLPartitionedSchema) SchemaInitializingQueries(topic string) []string {
+	table := p.MessagesTable(topic)
+
+	queries := []string{
+		strings.Join([]string{
+			`CREATE TABLE IF NOT EXISTS ` + table + ` (`,
+			`"offset" BIGSERIAL,`,
+			`"uuid" UUID NOT NULL,`,
+			`"created_at" TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,`,
+			`"payload" BYTEA DEFAULT NULL,`,
+			`"metadata" JSON DEFAULT NULL,`,
+			`PRIMARY KEY ("offset", "created_at")`,
+			`) PARTITION BY RANGE ("created_at");`,
+		}, "\n"),
+	}
+
+	now := time.Now().UTC()
+	for i := 0; i < partitionWindow; i++ {
+		monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, i, 0)
+		monthEnd := monthStart.AddDate(0, 1, 0)
+
+		partition := fmt.Sprintf("%s_%s", table, monthStart.Format("200601"))
+		queries = append(queries, fmt.Sprintf(
+			`CREATE TABLE IF NOT EXISTS %s PARTITION OF %s FOR VALUES FROM ('%s') TO ('%s');`,
+			partition, table, monthStart.Format(time.RFC3339), monthEnd.Format(time.RFC3339),
+		))
+	}
+
+	return queries
+}
+
+// MySQLPartitionedSchema range-partitions the messages table by hour.
+type MySQLPartitionedSchema struct {
+	MySQLSchema
+}
+
+func (m MySQLPartitionedSchema) SchemaInitializingQueries(topic string) []string {
+	table := m.MessagesTable(topic)
+
+	now := time.Now().UTC()
+	partitions := make([]string, 0, partitionWindow)
+	for i := 0; i < partitionWindow; i++ {
+		hourStart := now.Truncate(time.Hour).Add(time.Duration(i) * time.Hour)
+		hourEnd := hourStart.Add(time.Hour)
+
+		partitions = append(partitions, fmt.Sprintf(
+			"PARTITION p%s VALUES LESS THAN (%d)",
+			hourStart.Format("2006010215"), hourEnd.Unix(),
+		))
+	}
+
+	createMessagesTable := strings.Join([]string{
+		"CREATE TABLE IF NOT EXISTS " + table + " (",
+		"`offset` BIGINT NOT NULL AUTO_INCREMENT,",
+		"`uuid` BINARY(16) NOT NULL,",
+		"`created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,",
+		"`payload` BLOB DEFAULT NULL,",
+		"`metadata` JSON DEFAULT NULL,",
+		"PRIMARY KEY (`offset`, `created_at`)",
+		") PARTITION BY RANGE (UNIX_TIMESTAMP(`created_at`)) (",
+		strings.Join(partitions, ",\n") + ",",
+		"PARTITION pmax VALUES LESS THAN MAXVALUE",
+		");",
+	}, "\n")
+
+	return []string{createMessagesTable}
+}
+
+// sqlSchema is sql.SchemaAdapter plus MessagesTable (promoted from
+// DefaultMySQLSchema/DefaultPostgreSQLSchema, not part of the interface
+// itself), which BatchedPublisher needs to build its INSERT.
+type sqlSchema interface {
+	sql.SchemaAdapter
+	MessagesTable(topic string) string
+}
+
+// BatchedPublisher coalesces pending messages into a single multi-row
+// INSERT, flushing on batchSize or flushInterval, whichever comes first.
+type BatchedPublisher struct {
+	db            *stdSQL.DB
+	schema        sqlSchema
+	placeholder   func(argIndex int) string
+	batchSize     int
+	flushInterval time.Duration
+
+	initOnce sync.Once
+	initErr  error
+	table    string
+
+	pending chan batchedMessage
+	done    chan struct{}
+	stopped chan struct{}
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+type batchedMessage struct {
+	uuid     string
+	payload  []byte
+	metadata []byte
+}
+
+// NewMySQLBatchedPublisher creates a BatchedPublisher for MySQLSchema.
+func NewMySQLBatchedPublisher(db *stdSQL.DB, batchSize int, flushInterval time.Duration) *BatchedPublisher {
+	return newBatchedPublisher(db, MySQLSchema{}, func(int) string { return "?" }, batchSize, flushInterval)
+}
+
+// NewPostgreSQLBatchedPublisher creates a BatchedPublisher for PostgreSQLSchema.
+func NewPostgreSQLBatchedPublisher(db *stdSQL.DB, batchSize int, flushInterval time.Duration) *BatchedPublisher {
+	return newBatchedPublisher(db, PostgreSQLSchema{}, func(argIndex int) string { return fmt.Sprintf("$%d", argIndex) }, batchSize, flushInterval)
+}
+
+func newBatchedPublisher(db *stdSQL.DB, schema sqlSchema, placeholder func(int) string, batchSize int, flushInterval time.Duration) *BatchedPublisher {
+	p := &BatchedPublisher{
+		db:            db,
+		schema:        schema,
+		placeholder:   placeholder,
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		pending:       make(chan batchedMessage),
+		done:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+func (p *BatchedPublisher) Publish(topic string, messages ...*message.Message) error {
+	p.initOnce.Do(func() {
+		p.table = p.schema.MessagesTable(topic)
+		for _, query := range p.schema.SchemaInitializingQueries(topic) {
+			if _, err := p.db.Exec(query); err != nil {
+				p.initErr = err
+				return
+			}
+		}
+	})
+	if p.initErr != nil {
+		return p.initErr
+	}
+
+	for _, msg := range messages {
+		metadata, err := json.Marshal(msg.Metadata)
+		if err != nil {
+			return err
+		}
+
+		select {
+		case p.pending <- batchedMessage{uuid: msg.UUID, payload: msg.Payload, metadata: metadata}:
+		case <-p.done:
+			return fmt.Errorf("publisher is closed")
+		}
+	}
+	return nil
+}
+
+func (p *BatchedPublisher) run() {
+	defer close(p.stopped)
+
+	batch := make([]batchedMessage, 0, p.batchSize)
+	ticker := time.NewTicker(p.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case msg := <-p.pending:
+			batch = append(batch, msg)
+			if len(batch) >= p.batchSize {
+				p.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				p.flush(batch)
+				batch = batch[:0]
+			}
+		case <-p.done:
+			if len(batch) > 0 {
+				p.flush(batch)
+			}
+			return
+		}
+	}
+}
+
+func (p *BatchedPublisher) flush(batch []batchedMessage) {
+	values := make([]string, len(batch))
+	args := make([]interface{}, 0, len(batch)*3)
+
+	for i, msg := range batch {
+		base := i * 3
+		values[i] = fmt.Sprintf("(%s, %s, %s)", p.placeholder(base+1), p.placeholder(base+2), p.placeholder(base+3))
+		args = append(args, msg.uuid, msg.payload, msg.metadata)
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (uuid, payload, metadata) VALUES %s", p.table, strings.Join(values, ", "))
+	if _, err := p.db.Exec(query, args...); err != nil {
+		panic(err)
+	}
+}
+
+func (p *BatchedPublisher) Close() error {
+	p.closeOnce.Do(func() {
+		close(p.done)
+	})
+	<-p.stopped
+	return p.closeErr
+}
+
+var (
+	_ message.Publisher = &BatchedPublisher{}
+	_ sql.SchemaAdapter = PostgreSQLPartitionedSchema{}
+	_ sql.SchemaAdapter = MySQLPartitionedSchema{}
+)